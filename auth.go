@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User is the authenticated caller resolved from a bearer token.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	IsAdmin  bool   `json:"isAdmin"`
+}
+
+type tokenRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// issueTokenHandler checks a username/password pair against the users
+// table and, on success, mints a new bearer token for it.
+func issueTokenHandler(c *gin.Context) {
+	var body tokenRequest
+	if err := c.BindJSON(&body); err != nil || body.Username == "" || body.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username and password are required"})
+		return
+	}
+
+	var userID int64
+	var passwordHash string
+	err := db.QueryRowContext(c.Request.Context(),
+		"SELECT id, password_hash FROM users WHERE username = $1", body.Username).
+		Scan(&userID, &passwordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query error"})
+		}
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(body.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	_, err = db.ExecContext(c.Request.Context(),
+		"INSERT INTO bearer_tokens (token, user_id) VALUES ($1, $2)", token, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to persist token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// authenticate resolves the request's bearer token, if any, into the
+// user that owns it.
+func authenticate(c *gin.Context) (*User, error) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, errors.New("missing bearer token")
+	}
+
+	var u User
+	err := db.QueryRowContext(c.Request.Context(), `
+		SELECT users.id, users.username, users.is_admin
+		FROM bearer_tokens
+		JOIN users ON users.id = bearer_tokens.user_id
+		WHERE bearer_tokens.token = $1`, token).
+		Scan(&u.ID, &u.Username, &u.IsAdmin)
+	if err != nil {
+		return nil, errors.New("invalid or expired token")
+	}
+	return &u, nil
+}
+
+// RequireAuth rejects requests without a valid bearer token and stores
+// the resolved user on the context under "user" for handlers to read via
+// currentUser.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := authenticate(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Set("user", user)
+		c.Next()
+	}
+}
+
+func currentUser(c *gin.Context) *User {
+	if u, ok := c.Get("user"); ok {
+		return u.(*User)
+	}
+	return nil
+}
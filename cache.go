@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// githubCacheTTL is how long a cached release list is served without
+// even attempting to revalidate it with GitHub.
+const githubCacheTTL = 5 * time.Minute
+
+// rateLimitLowWatermark is the X-RateLimit-Remaining threshold at or
+// below which a repository that already has a cache entry is served from
+// it, even if stale, instead of calling the GitHub API, to avoid tripping
+// the limit entirely. Repositories with no cache entry are still fetched.
+const rateLimitLowWatermark = 3
+
+// githubCacheMaxAge bounds how long an entry may sit unused before the
+// janitor reclaims it, so the cache can't grow without limit as
+// repositories are added and removed over the service's lifetime.
+const githubCacheMaxAge = 24 * time.Hour
+
+// githubCacheEvictionInterval is how often the janitor sweeps for stale
+// entries.
+const githubCacheEvictionInterval = time.Hour
+
+// githubCacheEntry holds the last known release list for a repo along
+// with the validators needed to make a conditional request next time.
+type githubCacheEntry struct {
+	releases     []githubRelease
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+}
+
+var (
+	githubCacheMu sync.Mutex
+	githubCache   = map[string]*githubCacheEntry{}
+
+	githubRateMu        sync.Mutex
+	githubRateRemaining = -1 // -1 means unknown: no response observed yet
+	githubRateReset     time.Time
+)
+
+// GitHubRateLimitStatus is surfaced on /health so operators can see how
+// close the service is to being throttled.
+type GitHubRateLimitStatus struct {
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt,omitempty"`
+	Known     bool      `json:"known"`
+}
+
+func currentGitHubRateLimit() GitHubRateLimitStatus {
+	githubRateMu.Lock()
+	defer githubRateMu.Unlock()
+
+	if githubRateRemaining < 0 {
+		return GitHubRateLimitStatus{Known: false}
+	}
+	return GitHubRateLimitStatus{Remaining: githubRateRemaining, ResetAt: githubRateReset, Known: true}
+}
+
+func recordGitHubRateLimit(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	resetAt := time.Time{}
+	if resetUnix, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resetAt = time.Unix(resetUnix, 0)
+	}
+
+	githubRateMu.Lock()
+	githubRateRemaining = remaining
+	githubRateReset = resetAt
+	githubRateMu.Unlock()
+}
+
+// fetchGitHubReleases fetches the release list for ref, transparently
+// caching the result and using conditional requests (ETag / Last-
+// Modified) so that repeat calls within githubCacheTTL, or calls made
+// while the rate limit is nearly exhausted, don't consume a fresh GitHub
+// API request.
+func fetchGitHubReleases(ctx context.Context, ref string) ([]githubRelease, error) {
+	target := fmt.Sprintf("https://api.github.com/repos/%s/releases", ref)
+
+	githubCacheMu.Lock()
+	entry := githubCache[target]
+	githubCacheMu.Unlock()
+
+	if entry != nil {
+		fresh := time.Since(entry.fetchedAt) < githubCacheTTL
+		rate := currentGitHubRateLimit()
+		lowOnQuota := rate.Known && rate.Remaining <= rateLimitLowWatermark
+		if fresh || lowOnQuota {
+			return entry.releases, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	if entry != nil {
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	recordGitHubRateLimit(resp.Header)
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		githubCacheMu.Lock()
+		entry.fetchedAt = time.Now()
+		githubCacheMu.Unlock()
+		return entry.releases, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch data from GitHub: %s", resp.Status)
+	}
+
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, err
+	}
+
+	githubCacheMu.Lock()
+	githubCache[target] = &githubCacheEntry{
+		releases:     releases,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		fetchedAt:    time.Now(),
+	}
+	githubCacheMu.Unlock()
+
+	return releases, nil
+}
+
+// startGitHubCacheEvictor runs until ctx is cancelled, periodically
+// dropping cache entries older than githubCacheMaxAge so repositories
+// that are deleted (or simply stop being polled) don't pin memory
+// forever. It mirrors the ticker pattern already used by
+// startReleasePoller.
+func startGitHubCacheEvictor(ctx context.Context) {
+	ticker := time.NewTicker(githubCacheEvictionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			evictStaleGitHubCacheEntries()
+		}
+	}
+}
+
+func evictStaleGitHubCacheEntries() {
+	cutoff := time.Now().Add(-githubCacheMaxAge)
+
+	githubCacheMu.Lock()
+	defer githubCacheMu.Unlock()
+
+	for target, entry := range githubCache {
+		if entry.fetchedAt.Before(cutoff) {
+			delete(githubCache, target)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "sync"
+
+// eventBroker fans release events out to every SSE client currently
+// connected to /events/:name for a given short name.
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan []byte]struct{}
+}
+
+var releaseEvents = &eventBroker{subs: map[string]map[chan []byte]struct{}{}}
+
+// Subscribe registers a new listener for shortName and returns the
+// channel it should read events from.
+func (b *eventBroker) Subscribe(shortName string) chan []byte {
+	ch := make(chan []byte, 8)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[shortName] == nil {
+		b.subs[shortName] = map[chan []byte]struct{}{}
+	}
+	b.subs[shortName][ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe removes and closes a previously subscribed channel.
+func (b *eventBroker) Unsubscribe(shortName string, ch chan []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if listeners, ok := b.subs[shortName]; ok {
+		delete(listeners, ch)
+		if len(listeners) == 0 {
+			delete(b.subs, shortName)
+		}
+	}
+	close(ch)
+}
+
+// Publish sends payload to every listener currently subscribed to
+// shortName, dropping it for any listener whose buffer is full rather
+// than blocking the poller.
+func (b *eventBroker) Publish(shortName string, payload []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[shortName] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+}
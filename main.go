@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -9,7 +10,6 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
@@ -17,29 +17,35 @@ import (
 	_ "github.com/lib/pq"
 )
 
-// Define the struct for the GitHub API response
-type Asset struct {
-	Name               string `json:"name"`
-	BrowserDownloadURL string `json:"browser_download_url"`
-}
-
-type Release struct {
-	TagName string  `json:"tag_name"`
-	Assets  []Asset `json:"assets"`
-}
-
 // Define the struct for the request body
 type RequestBody struct {
-	ShortName string `json:"shortName"`
-	Github    string `json:"github"`
-	Filter    string `json:"filter"`
+	ShortName  string `json:"shortName"`
+	Github     string `json:"github"`
+	Filter     string `json:"filter"`
+	Channel    string `json:"channel"`
+	Constraint string `json:"constraint"`
+	Public     *bool  `json:"public"`
 }
 
 // Define the struct for the repository item
 type Repository struct {
-	ShortName string `json:"shortName"`
-	GithubURL string `json:"githubURL"`
-	Filter    string `json:"filter"`
+	ShortName  string `json:"shortName"`
+	Source     string `json:"source"`
+	Ref        string `json:"ref"`
+	Filter     string `json:"filter"`
+	Channel    string `json:"channel"`
+	Constraint string `json:"constraint"`
+	UserID     int64  `json:"userId"`
+	Public     bool   `json:"public"`
+}
+
+// updateRepositoryRequest carries the fields PUT /repositories/:name may
+// change; a nil field leaves the stored value untouched.
+type updateRepositoryRequest struct {
+	Filter     *string `json:"filter"`
+	Channel    *string `json:"channel"`
+	Constraint *string `json:"constraint"`
+	Public     *bool   `json:"public"`
 }
 
 var db *sql.DB
@@ -67,27 +73,9 @@ func init() {
 	}
 }
 
-func fetchGitHubData(repoURL string) ([]Release, error) {
-	resp, err := http.Get(repoURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch data from GitHub: %s", resp.Status)
-	}
-
-	var releases []Release
-	err = json.NewDecoder(resp.Body).Decode(&releases)
-	if err != nil {
-		return nil, err
-	}
-
-	return releases, nil
-}
-
 func newHandler(c *gin.Context) {
+	owner := currentUser(c)
+
 	var body RequestBody
 	if err := c.BindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
@@ -95,11 +83,27 @@ func newHandler(c *gin.Context) {
 	}
 
 	shortName := body.ShortName
-	githubURL := body.Github
+	source, ref := SplitSourceRef(body.Github)
 	filter := body.Filter
 
-	// Insert the data into the database
-	_, err := db.Exec("INSERT INTO repositories (short_name, github_url, filter) VALUES ($1, $2, $3)", shortName, githubURL, filter)
+	channel := body.Channel
+	if channel == "" {
+		channel = ChannelStable
+	}
+
+	// Repositories are private (owner-only) by default; publishing is an
+	// explicit opt-in via "public": true.
+	public := false
+	if body.Public != nil {
+		public = *body.Public
+	}
+
+	// Insert the data into the database. github_url keeps its historical
+	// name but now holds the provider ref (owner/repo, namespace/image, ...)
+	// rather than always being a GitHub URL.
+	_, err := db.ExecContext(c.Request.Context(),
+		"INSERT INTO repositories (short_name, source, github_url, filter, channel, version_constraint, user_id, public) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		shortName, source, ref, filter, channel, body.Constraint, owner.ID, public)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert data into the database"})
 		return
@@ -108,74 +112,140 @@ func newHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Data inserted successfully"})
 }
 
-func versionHandler(c *gin.Context) {
-	shortName := c.Param("name")
-
-	// Query the database for the GitHub repository URL associated with the shortName
-	var repoURL string
-	err := db.QueryRow("SELECT github_url FROM repositories WHERE short_name = $1", shortName).Scan(&repoURL)
+// selectPolledRelease loads the full release list the background poller
+// last stored for shortName and picks the best match for channel
+// (defaulting to the repository's configured channel) and its semver
+// constraint, writing an appropriate error response and returning
+// ok=false if it can't be found, hasn't been polled yet, or nothing
+// matches.
+func selectPolledRelease(c *gin.Context, shortName string) (release Release, filter string, ok bool) {
+	var (
+		releasesJSON []byte
+		channel      string
+		constraint   string
+		public       bool
+		ownerID      int64
+	)
+	err := db.QueryRowContext(c.Request.Context(),
+		"SELECT filter, channel, version_constraint, releases_json, public, user_id FROM repositories WHERE short_name = $1", shortName,
+	).Scan(&filter, &channel, &constraint, &releasesJSON, &public, &ownerID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query error"})
 		}
-		return
+		return Release{}, "", false
 	}
 
-	// Fetch releases from the GitHub API
-	releases, err := fetchGitHubData(repoURL)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch data from GitHub"})
-		return
+	if !public {
+		// Private repositories require authentication as the owner (or
+		// an admin); report them as not found rather than forbidden so
+		// their existence isn't leaked to unauthorized callers.
+		user, err := authenticate(c)
+		if err != nil || (!user.IsAdmin && user.ID != ownerID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+			return Release{}, "", false
+		}
+	}
+
+	if override := c.Query("channel"); override != "" {
+		channel = override
 	}
 
-	// Return the latest version tag
-	if len(releases) > 0 {
-		latestVersion := releases[0].TagName
-		c.String(http.StatusOK, latestVersion)
-	} else {
+	var releases []Release
+	if len(releasesJSON) > 0 {
+		if err := json.Unmarshal(releasesJSON, &releases); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode stored release data"})
+			return Release{}, "", false
+		}
+	}
+
+	release, matched := selectRelease(releases, channel, constraint)
+	if !matched {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No versions found"})
+		return Release{}, "", false
+	}
+
+	return release, filter, true
+}
+
+func versionHandler(c *gin.Context) {
+	shortName := c.Param("name")
+
+	release, _, ok := selectPolledRelease(c, shortName)
+	if !ok {
+		return
 	}
+
+	c.String(http.StatusOK, release.TagName)
 }
 
 func downloadHandler(c *gin.Context) {
 	shortName := c.Param("name")
 
-	// Query the database for the GitHub repository URL and filter criteria associated with the shortName
-	var repoURL, filter string
-	err := db.QueryRow("SELECT github_url, filter FROM repositories WHERE short_name = $1", shortName).Scan(&repoURL, &filter)
+	release, filter, ok := selectPolledRelease(c, shortName)
+	if !ok {
+		return
+	}
+
+	asset, err := findAssetByFilter(release.Assets, filter)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query error"})
-		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "No asset found matching the filter"})
+		return
+	}
+
+	if asset.Kind == AssetKindCommand {
+		c.String(http.StatusOK, asset.DownloadURL)
 		return
 	}
 
-	// Fetch releases from the GitHub API
-	releases, err := fetchGitHubData(repoURL)
+	c.Redirect(http.StatusSeeOther, asset.DownloadURL)
+}
+
+// listHandler is the legacy unauthenticated listing; it only surfaces
+// public repositories. Authenticated callers should use /repositories to
+// also see their own private ones.
+func listHandler(c *gin.Context) {
+	rows, err := db.QueryContext(c.Request.Context(),
+		"SELECT short_name, source, github_url, filter, channel, version_constraint FROM repositories WHERE public")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch data from GitHub"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query the database"})
 		return
 	}
+	defer rows.Close()
 
-	// Find the asset with a name containing the filter string
-	for _, release := range releases {
-		for _, asset := range release.Assets {
-			if strings.Contains(asset.Name, filter) {
-				c.Redirect(http.StatusSeeOther, asset.BrowserDownloadURL)
-				return
-			}
+	var repositories []Repository
+	for rows.Next() {
+		var repo Repository
+		if err := rows.Scan(&repo.ShortName, &repo.Source, &repo.Ref, &repo.Filter, &repo.Channel, &repo.Constraint); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan database rows"})
+			return
 		}
+		repositories = append(repositories, repo)
+	}
+
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while iterating rows"})
+		return
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{"error": "No asset found matching the filter"})
+	c.JSON(http.StatusOK, repositories)
 }
 
-func listHandler(c *gin.Context) {
-	rows, err := db.Query("SELECT short_name, github_url, filter FROM repositories")
+// listOwnRepositoriesHandler returns every repository the caller owns,
+// or every repository in the system for an admin.
+func listOwnRepositoriesHandler(c *gin.Context) {
+	user := currentUser(c)
+
+	query := "SELECT short_name, source, github_url, filter, channel, version_constraint, user_id, public FROM repositories"
+	args := []interface{}{}
+	if !user.IsAdmin {
+		query += " WHERE user_id = $1"
+		args = append(args, user.ID)
+	}
+
+	rows, err := db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query the database"})
 		return
@@ -185,7 +255,7 @@ func listHandler(c *gin.Context) {
 	var repositories []Repository
 	for rows.Next() {
 		var repo Repository
-		if err := rows.Scan(&repo.ShortName, &repo.GithubURL, &repo.Filter); err != nil {
+		if err := rows.Scan(&repo.ShortName, &repo.Source, &repo.Ref, &repo.Filter, &repo.Channel, &repo.Constraint, &repo.UserID, &repo.Public); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan database rows"})
 			return
 		}
@@ -200,6 +270,72 @@ func listHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, repositories)
 }
 
+// authorizeRepositoryOwner loads the owner of shortName and confirms the
+// caller is allowed to modify it (the owner, or an admin).
+func authorizeRepositoryOwner(c *gin.Context, shortName string) bool {
+	user := currentUser(c)
+
+	var ownerID int64
+	err := db.QueryRowContext(c.Request.Context(),
+		"SELECT user_id FROM repositories WHERE short_name = $1", shortName).Scan(&ownerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query error"})
+		}
+		return false
+	}
+
+	if !user.IsAdmin && user.ID != ownerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not the owner of this repository"})
+		return false
+	}
+	return true
+}
+
+func updateRepositoryHandler(c *gin.Context) {
+	shortName := c.Param("name")
+	if !authorizeRepositoryOwner(c, shortName) {
+		return
+	}
+
+	var body updateRepositoryRequest
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	_, err := db.ExecContext(c.Request.Context(), `
+		UPDATE repositories SET
+			filter = COALESCE($1, filter),
+			channel = COALESCE($2, channel),
+			version_constraint = COALESCE($3, version_constraint),
+			public = COALESCE($4, public)
+		WHERE short_name = $5`,
+		body.Filter, body.Channel, body.Constraint, body.Public, shortName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository updated"})
+}
+
+func deleteRepositoryHandler(c *gin.Context) {
+	shortName := c.Param("name")
+	if !authorizeRepositoryOwner(c, shortName) {
+		return
+	}
+
+	if _, err := db.ExecContext(c.Request.Context(), "DELETE FROM repositories WHERE short_name = $1", shortName); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository deleted"})
+}
+
 // LoggerMiddleware logs details of each request
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -221,7 +357,7 @@ func LoggerMiddleware() gin.HandlerFunc {
 // Function to perform the health check
 func performHealthCheck() {
 	for {
-		err := db.Ping()
+		err := db.PingContext(context.Background())
 		if err != nil {
 			log.Printf("Health check failed: %v\n", err)
 		} else {
@@ -232,7 +368,7 @@ func performHealthCheck() {
 }
 
 func healthHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, nil)
+	c.JSON(http.StatusOK, gin.H{"githubRateLimit": currentGitHubRateLimit()})
 }
 
 func main() {
@@ -251,34 +387,75 @@ func main() {
 	r := gin.Default()
 
 	r.Use(LoggerMiddleware())
-	r.GET("/health", healthHandler)
-	// Define the /new endpoint
-	r.POST("/new", newHandler)
+
+	// The SSE event stream is long-lived by design, so it's registered
+	// directly on the bare engine, outside of the group below, to keep
+	// it from inheriting the request timeout meant for finite handlers.
+	// The stream stays public; subscribing a webhook requires owning the
+	// repository, so that endpoint lives in the authorized group below.
+	r.GET("/events/:name", eventsHandler)
+
+	// Every other route gets a deadline on its request context.
+	timed := r.Group("/")
+	timed.Use(RequestTimeoutMiddleware(requestTimeout()))
+	timed.GET("/health", healthHandler)
+
+	timed.POST("/auth/token", issueTokenHandler)
 
 	// Define the /version/{name} endpoint
-	r.GET("/version/:name", versionHandler)
+	timed.GET("/version/:name", versionHandler)
 
 	// Define the /download/{name} endpoint
-	r.GET("/download/:name", downloadHandler)
+	timed.GET("/download/:name", downloadHandler)
 
 	// Define the /list endpoint
-	r.GET("/list", listHandler)
+	timed.GET("/list", listHandler)
+
+	// Authenticated admin API: registering, listing, and managing
+	// repositories the caller owns.
+	authorized := timed.Group("/")
+	authorized.Use(RequireAuth())
+	authorized.POST("/new", newHandler)
+	authorized.GET("/repositories", listOwnRepositoriesHandler)
+	authorized.PUT("/repositories/:name", updateRepositoryHandler)
+	authorized.DELETE("/repositories/:name", deleteRepositoryHandler)
+	authorized.POST("/subscriptions", subscribeHandler)
 
 	go performHealthCheck()
 
+	pollerCtx, cancelPoller := context.WithCancel(context.Background())
+	go startReleasePoller(pollerCtx)
+	go startGitHubCacheEvictor(pollerCtx)
+
 	addr := fmt.Sprintf(":%d", port)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: r,
+	}
+
 	go func() {
-		if err := r.Run(addr); err != nil {
-			log.Fatal(err)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server error: %v", err)
 		}
 	}()
 
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, os.Interrupt, syscall.SIGTERM)
-	select {
-	case <-sigterm:
-		log.Fatal("terminating: by signal")
+	<-sigterm
+	log.Println("received shutdown signal, draining in-flight requests")
+
+	cancelPoller()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("graceful shutdown failed: %v", err)
 	}
-	log.Fatal("shutting down")
-	os.Exit(0)
+
+	if err := db.Close(); err != nil {
+		log.Printf("failed to close database connection: %v", err)
+	}
+
+	log.Println("shut down cleanly")
 }
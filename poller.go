@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pollInterval controls how often the background poller refreshes every
+// registered repository's releases. Configurable via POLL_INTERVAL_MINUTES
+// so deployments with many repositories can back off GitHub.
+func pollInterval() time.Duration {
+	if v := os.Getenv("POLL_INTERVAL_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Minute
+		}
+	}
+	return 5 * time.Minute
+}
+
+type polledRepo struct {
+	ShortName  string
+	Source     string
+	Ref        string
+	Filter     string
+	Channel    string
+	Constraint string
+	LatestTag  string
+}
+
+// startReleasePoller runs until ctx is cancelled, refreshing every
+// repository's releases on each tick, persisting the result and notifying
+// subscribers whenever a new tag appears. It mirrors the polling pattern
+// already used by performHealthCheck.
+func startReleasePoller(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pollAllRepositories(ctx)
+		}
+	}
+}
+
+func pollAllRepositories(ctx context.Context) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT short_name, source, github_url, filter, channel, version_constraint, COALESCE(latest_tag, '') FROM repositories")
+	if err != nil {
+		log.Printf("poller: failed to list repositories: %v", err)
+		return
+	}
+
+	var repos []polledRepo
+	for rows.Next() {
+		var r polledRepo
+		if err := rows.Scan(&r.ShortName, &r.Source, &r.Ref, &r.Filter, &r.Channel, &r.Constraint, &r.LatestTag); err != nil {
+			log.Printf("poller: failed to scan repository row: %v", err)
+			continue
+		}
+		repos = append(repos, r)
+	}
+	rows.Close()
+
+	for _, r := range repos {
+		pollRepository(ctx, r)
+	}
+}
+
+func pollRepository(ctx context.Context, r polledRepo) {
+	provider, err := ProviderFor(r.Source)
+	if err != nil {
+		log.Printf("poller: %s: %v", r.ShortName, err)
+		return
+	}
+
+	releases, err := provider.LatestRelease(ctx, r.Ref)
+	if err != nil {
+		log.Printf("poller: %s: failed to fetch releases: %v", r.ShortName, err)
+		return
+	}
+	if len(releases) == 0 {
+		return
+	}
+
+	releasesJSON, err := json.Marshal(releases)
+	if err != nil {
+		log.Printf("poller: %s: failed to marshal releases: %v", r.ShortName, err)
+		return
+	}
+
+	selected, ok := selectRelease(releases, r.Channel, r.Constraint)
+
+	_, err = db.ExecContext(ctx,
+		"UPDATE repositories SET latest_tag = $1, releases_json = $2, checked_at = $3 WHERE short_name = $4",
+		selected.TagName, releasesJSON, time.Now(), r.ShortName)
+	if err != nil {
+		log.Printf("poller: %s: failed to persist release: %v", r.ShortName, err)
+		return
+	}
+
+	if ok && selected.TagName != "" && selected.TagName != r.LatestTag {
+		notifyRelease(ctx, r.ShortName, selected)
+	}
+}
+
+// notifyRelease fans a new release out to every interested party: the SSE
+// broker for /events/:name and any outbound webhooks registered via
+// /subscriptions.
+func notifyRelease(ctx context.Context, shortName string, release Release) {
+	payload, err := json.Marshal(gin.H{
+		"shortName": shortName,
+		"tag":       release.TagName,
+		"assets":    release.Assets,
+	})
+	if err != nil {
+		log.Printf("poller: %s: failed to marshal release event: %v", shortName, err)
+		return
+	}
+
+	releaseEvents.Publish(shortName, payload)
+	deliverWebhooks(ctx, shortName, payload)
+}
+
+// --- Subscriptions (outbound webhooks) ---
+
+type subscribeRequest struct {
+	ShortName string `json:"shortName"`
+	TargetURL string `json:"targetUrl"`
+	Secret    string `json:"secret"`
+}
+
+func subscribeHandler(c *gin.Context) {
+	var body subscribeRequest
+	if err := c.BindJSON(&body); err != nil || body.ShortName == "" || body.TargetURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "shortName and targetUrl are required"})
+		return
+	}
+
+	if body.Secret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "secret is required"})
+		return
+	}
+
+	// Only the repository's owner (or an admin) may point a webhook at
+	// it, otherwise anyone could register arbitrary outbound requests
+	// against a target of their choosing.
+	if !authorizeRepositoryOwner(c, body.ShortName) {
+		return
+	}
+
+	_, err := db.ExecContext(c.Request.Context(), "INSERT INTO subscriptions (short_name, target_url, secret) VALUES ($1, $2, $3)",
+		body.ShortName, body.TargetURL, body.Secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription registered"})
+}
+
+func deliverWebhooks(ctx context.Context, shortName string, payload []byte) {
+	rows, err := db.QueryContext(ctx, "SELECT target_url, secret FROM subscriptions WHERE short_name = $1", shortName)
+	if err != nil {
+		log.Printf("webhook: %s: failed to list subscriptions: %v", shortName, err)
+		return
+	}
+	defer rows.Close()
+
+	type target struct{ url, secret string }
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.url, &t.secret); err != nil {
+			continue
+		}
+		targets = append(targets, t)
+	}
+
+	for _, t := range targets {
+		go deliverWebhook(t.url, t.secret, payload)
+	}
+}
+
+func deliverWebhook(targetURL, secret string, payload []byte) {
+	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(payload))
+	if err != nil {
+		log.Printf("webhook: failed to build request for %s: %v", targetURL, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hub-Signature-256", signPayload(secret, payload))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("webhook: delivery to %s failed: %v", targetURL, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.Printf("webhook: delivery to %s returned %s", targetURL, resp.Status)
+	}
+}
+
+// signPayload signs payload the same way GitHub signs its webhook
+// deliveries, so existing webhook receivers can verify it unchanged.
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// --- Server-Sent Events ---
+
+func eventsHandler(c *gin.Context) {
+	shortName := c.Param("name")
+
+	ch := releaseEvents.Subscribe(shortName)
+	defer releaseEvents.Unsubscribe(shortName, ch)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return false
+			}
+			w.Write([]byte("data: "))
+			w.Write(msg)
+			w.Write([]byte("\n\n"))
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Release is the provider-agnostic view of a single release/tag that the
+// rest of the app works with, regardless of which ReleaseProvider produced
+// it. Draft, PreRelease and PublishedAt default to their zero values for
+// providers with no such concept (Docker Hub tags, SourceHut refs), which
+// is equivalent to treating every one of their releases as stable.
+type Release struct {
+	TagName     string    `json:"tagName"`
+	Assets      []Asset   `json:"assets"`
+	Draft       bool      `json:"draft"`
+	PreRelease  bool      `json:"preRelease"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// Asset kinds understood by downloadHandler.
+const (
+	// AssetKindRedirect means DownloadURL is a real URL the caller should
+	// be redirected to.
+	AssetKindRedirect = "redirect"
+	// AssetKindCommand means DownloadURL actually holds a shell command
+	// (e.g. "docker pull ...") that should be returned as plain text
+	// rather than redirected to.
+	AssetKindCommand = "command"
+)
+
+// Asset is a single downloadable artifact attached to a Release. For
+// providers that don't expose a direct download URL (e.g. Docker Hub),
+// DownloadURL holds whatever the user should be redirected to or shown
+// instead (a registry pull command, a manifest URL, ...), and Kind says
+// which.
+type Asset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"downloadUrl"`
+	Kind        string `json:"kind"`
+}
+
+// ReleaseProvider fetches release metadata for a single repository-like
+// resource identified by ref. The meaning of ref is provider-specific:
+// "owner/repo" for GitHub, "host/owner/repo" for Gitea, a numeric or path
+// project ID for GitLab, "namespace/image" for Docker Hub and
+// "~user/repo" for SourceHut.
+type ReleaseProvider interface {
+	// LatestRelease returns every release known to the provider, ordered
+	// newest-first, so callers can apply their own channel/semver
+	// filtering on top.
+	LatestRelease(ctx context.Context, ref string) ([]Release, error)
+	// FindAsset returns the first asset whose name matches filter, which
+	// is interpreted as a regular expression. An empty filter matches the
+	// first available asset.
+	FindAsset(release Release, filter string) (Asset, error)
+}
+
+// providers maps a source prefix (as used in "source:ref" short-name
+// registration) to the provider that serves it.
+var providers = map[string]ReleaseProvider{
+	"github":    &githubProvider{},
+	"gitlab":    &gitlabProvider{},
+	"dockerhub": &dockerHubProvider{},
+	"gitea":     &giteaProvider{},
+	"sourcehut": &sourcehutProvider{},
+}
+
+// ProviderFor returns the registered ReleaseProvider for source, or an
+// error if no provider is registered under that name.
+func ProviderFor(source string) (ReleaseProvider, error) {
+	p, ok := providers[source]
+	if !ok {
+		return nil, fmt.Errorf("unknown release source %q", source)
+	}
+	return p, nil
+}
+
+// SplitSourceRef splits a "source:ref" short-name (e.g.
+// "gitlab:group/project") into its source and ref parts. If no "source:"
+// prefix is present, source defaults to "github" for backward
+// compatibility with existing short names registered before multi-source
+// support existed.
+func SplitSourceRef(raw string) (source, ref string) {
+	if s, r, ok := strings.Cut(raw, ":"); ok {
+		if _, known := providers[s]; known {
+			return s, r
+		}
+	}
+	return "github", raw
+}
+
+// findAssetByFilter is the shared matching logic used by providers whose
+// assets are plain name/URL pairs: the first asset whose name matches the
+// filter regular expression wins, and an empty filter matches the first
+// asset present.
+func findAssetByFilter(assets []Asset, filter string) (Asset, error) {
+	if filter == "" {
+		if len(assets) == 0 {
+			return Asset{}, fmt.Errorf("release has no assets")
+		}
+		return assets[0], nil
+	}
+
+	re, err := regexp.Compile(filter)
+	if err != nil {
+		return Asset{}, fmt.Errorf("invalid filter %q: %w", filter, err)
+	}
+
+	for _, asset := range assets {
+		if re.MatchString(asset.Name) {
+			return asset, nil
+		}
+	}
+	return Asset{}, fmt.Errorf("no asset found matching filter %q", filter)
+}
+
+// getJSON performs an HTTP GET against target, sending the given headers
+// (if any), and decodes the JSON response body into out.
+func getJSON(ctx context.Context, target string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed: %s", target, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// --- GitHub ---
+
+type githubProvider struct{}
+
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Draft       bool      `json:"draft"`
+	PreRelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (p *githubProvider) LatestRelease(ctx context.Context, ref string) ([]Release, error) {
+	raw, err := fetchGitHubReleases(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(raw))
+	for _, r := range raw {
+		assets := make([]Asset, 0, len(r.Assets))
+		for _, a := range r.Assets {
+			assets = append(assets, Asset{Name: a.Name, DownloadURL: a.BrowserDownloadURL})
+		}
+		releases = append(releases, Release{
+			TagName:     r.TagName,
+			Assets:      assets,
+			Draft:       r.Draft,
+			PreRelease:  r.PreRelease,
+			PublishedAt: r.PublishedAt,
+		})
+	}
+	return releases, nil
+}
+
+func (p *githubProvider) FindAsset(release Release, filter string) (Asset, error) {
+	return findAssetByFilter(release.Assets, filter)
+}
+
+// --- GitLab ---
+
+type gitlabProvider struct{}
+
+type gitlabRelease struct {
+	TagName         string    `json:"tag_name"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	ReleasedAt      time.Time `json:"released_at"`
+	Assets          struct {
+		Links []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+func (p *gitlabProvider) LatestRelease(ctx context.Context, ref string) ([]Release, error) {
+	projectID := url.PathEscape(ref)
+	target := fmt.Sprintf("https://gitlab.com/api/v4/projects/%s/releases", projectID)
+
+	headers := map[string]string{}
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	}
+
+	var raw []gitlabRelease
+	if err := getJSON(ctx, target, headers, &raw); err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(raw))
+	for _, r := range raw {
+		assets := make([]Asset, 0, len(r.Assets.Links))
+		for _, link := range r.Assets.Links {
+			assets = append(assets, Asset{Name: link.Name, DownloadURL: link.URL})
+		}
+		releases = append(releases, Release{
+			TagName:     r.TagName,
+			Assets:      assets,
+			PreRelease:  r.UpcomingRelease,
+			PublishedAt: r.ReleasedAt,
+		})
+	}
+	return releases, nil
+}
+
+func (p *gitlabProvider) FindAsset(release Release, filter string) (Asset, error) {
+	return findAssetByFilter(release.Assets, filter)
+}
+
+// --- Docker Hub ---
+
+type dockerHubProvider struct{}
+
+type dockerHubTagList struct {
+	Results []struct {
+		Name        string `json:"name"`
+		LastUpdated string `json:"last_updated"`
+	} `json:"results"`
+}
+
+func (p *dockerHubProvider) LatestRelease(ctx context.Context, ref string) ([]Release, error) {
+	target := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags?page_size=100&ordering=last_updated", ref)
+
+	var raw dockerHubTagList
+	if err := getJSON(ctx, target, nil, &raw); err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(raw.Results))
+	for _, t := range raw.Results {
+		// Docker Hub has no downloadable asset URLs. We offer two
+		// "assets": a pull command (returned as plain text, not
+		// redirected to) and the tag's manifest page (a real URL, safe
+		// to redirect to).
+		pullCmd := fmt.Sprintf("docker pull %s:%s", ref, t.Name)
+		manifestURL := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/%s", ref, t.Name)
+		releases = append(releases, Release{
+			TagName: t.Name,
+			Assets: []Asset{
+				{Name: t.Name, DownloadURL: pullCmd, Kind: AssetKindCommand},
+				{Name: t.Name + "-manifest", DownloadURL: manifestURL, Kind: AssetKindRedirect},
+			},
+		})
+	}
+	return releases, nil
+}
+
+func (p *dockerHubProvider) FindAsset(release Release, filter string) (Asset, error) {
+	return findAssetByFilter(release.Assets, filter)
+}
+
+// --- Gitea ---
+
+type giteaProvider struct{}
+
+type giteaRelease struct {
+	TagName     string    `json:"tag_name"`
+	Draft       bool      `json:"draft"`
+	PreRelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (p *giteaProvider) LatestRelease(ctx context.Context, ref string) ([]Release, error) {
+	// ref is "host/owner/repo"; split off the host so we can hit that
+	// instance's API rather than a hardcoded one.
+	host, ownerRepo, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, fmt.Errorf("gitea ref %q must be host/owner/repo", ref)
+	}
+	target := fmt.Sprintf("https://%s/api/v1/repos/%s/releases", host, ownerRepo)
+
+	headers := map[string]string{}
+	if token := os.Getenv("GITEA_TOKEN"); token != "" {
+		headers["Authorization"] = "token " + token
+	}
+
+	var raw []giteaRelease
+	if err := getJSON(ctx, target, headers, &raw); err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(raw))
+	for _, r := range raw {
+		assets := make([]Asset, 0, len(r.Assets))
+		for _, a := range r.Assets {
+			assets = append(assets, Asset{Name: a.Name, DownloadURL: a.BrowserDownloadURL})
+		}
+		releases = append(releases, Release{
+			TagName:     r.TagName,
+			Assets:      assets,
+			Draft:       r.Draft,
+			PreRelease:  r.PreRelease,
+			PublishedAt: r.PublishedAt,
+		})
+	}
+	return releases, nil
+}
+
+func (p *giteaProvider) FindAsset(release Release, filter string) (Asset, error) {
+	return findAssetByFilter(release.Assets, filter)
+}
+
+// --- SourceHut ---
+
+type sourcehutProvider struct{}
+
+// sourcehut has no first-class "releases" concept; git tags are the
+// closest analogue, so we list refs and treat each tag ref as a release
+// with no attached assets.
+type sourcehutRefList struct {
+	Results []struct {
+		Name string `json:"name"`
+	} `json:"results"`
+}
+
+func (p *sourcehutProvider) LatestRelease(ctx context.Context, ref string) ([]Release, error) {
+	user, repo, ok := strings.Cut(strings.TrimPrefix(ref, "~"), "/")
+	if !ok {
+		return nil, fmt.Errorf("sourcehut ref %q must be ~user/repo", ref)
+	}
+	target := fmt.Sprintf("https://git.sr.ht/api/~%s/repos/%s/refs", user, repo)
+
+	headers := map[string]string{}
+	if token := os.Getenv("SOURCEHUT_TOKEN"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	var raw sourcehutRefList
+	if err := getJSON(ctx, target, headers, &raw); err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(raw.Results))
+	for _, t := range raw.Results {
+		if !strings.HasPrefix(t.Name, "refs/tags/") {
+			continue
+		}
+		releases = append(releases, Release{TagName: strings.TrimPrefix(t.Name, "refs/tags/")})
+	}
+	return releases, nil
+}
+
+func (p *sourcehutProvider) FindAsset(release Release, filter string) (Asset, error) {
+	return findAssetByFilter(release.Assets, filter)
+}
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestSplitSourceRef(t *testing.T) {
+	cases := []struct {
+		raw        string
+		wantSource string
+		wantRef    string
+	}{
+		{"owner/repo", "github", "owner/repo"},
+		{"github:owner/repo", "github", "owner/repo"},
+		{"gitlab:group/project", "gitlab", "group/project"},
+		{"dockerhub:namespace/image", "dockerhub", "namespace/image"},
+		{"gitea:git.example.com/owner/repo", "gitea", "git.example.com/owner/repo"},
+		{"sourcehut:~user/repo", "sourcehut", "~user/repo"},
+		// An unknown prefix isn't treated as a source; the whole string
+		// is passed through as a github ref, same as the pre-multi-source
+		// behavior.
+		{"not-a-source:owner/repo", "github", "not-a-source:owner/repo"},
+	}
+
+	for _, tc := range cases {
+		source, ref := SplitSourceRef(tc.raw)
+		if source != tc.wantSource || ref != tc.wantRef {
+			t.Errorf("SplitSourceRef(%q) = (%q, %q), want (%q, %q)", tc.raw, source, ref, tc.wantSource, tc.wantRef)
+		}
+	}
+}
+
+func TestFindAssetByFilter(t *testing.T) {
+	assets := []Asset{
+		{Name: "app-linux-amd64.tar.gz"},
+		{Name: "app-darwin-amd64.tar.gz"},
+		{Name: "app-windows-amd64.zip"},
+	}
+
+	got, err := findAssetByFilter(assets, "linux")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "app-linux-amd64.tar.gz" {
+		t.Errorf("got %q, want app-linux-amd64.tar.gz", got.Name)
+	}
+
+	// Filter is a regex, not a plain substring.
+	got, err = findAssetByFilter(assets, `windows-amd64\.zip$`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "app-windows-amd64.zip" {
+		t.Errorf("got %q, want app-windows-amd64.zip", got.Name)
+	}
+
+	if _, err := findAssetByFilter(assets, "freebsd"); err == nil {
+		t.Error("expected an error when no asset matches the filter")
+	}
+
+	if _, err := findAssetByFilter(assets, "["); err == nil {
+		t.Error("expected an error for an invalid regex filter")
+	}
+
+	got, err = findAssetByFilter(assets, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != assets[0].Name {
+		t.Errorf("empty filter should match the first asset, got %q", got.Name)
+	}
+
+	if _, err := findAssetByFilter(nil, ""); err == nil {
+		t.Error("expected an error when there are no assets to match")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Channel names accepted for a repository's release channel.
+const (
+	ChannelStable     = "stable"
+	ChannelPrerelease = "prerelease"
+	ChannelAny        = "any"
+)
+
+// canonicalSemver makes tag tolerant of the common "no leading v" style
+// (e.g. "1.2.3") so it can be fed to golang.org/x/mod/semver, which
+// requires the "v" prefix.
+func canonicalSemver(tag string) string {
+	if strings.HasPrefix(tag, "v") {
+		return tag
+	}
+	return "v" + tag
+}
+
+// filterByChannel drops releases that don't belong on channel:
+//   - stable: only non-draft, non-prerelease releases
+//   - prerelease: non-draft releases, including prereleases
+//   - any: everything, including drafts
+func filterByChannel(releases []Release, channel string) []Release {
+	var filtered []Release
+	for _, r := range releases {
+		switch channel {
+		case ChannelAny:
+			filtered = append(filtered, r)
+		case ChannelPrerelease:
+			if !r.Draft {
+				filtered = append(filtered, r)
+			}
+		default: // ChannelStable
+			if !r.Draft && !r.PreRelease {
+				filtered = append(filtered, r)
+			}
+		}
+	}
+	return filtered
+}
+
+// matchesConstraint reports whether tag satisfies constraint, a
+// comma-separated list of clauses such as ">=1.2.0,<2.0.0". An empty
+// constraint always matches.
+func matchesConstraint(tag, constraint string) (bool, error) {
+	if constraint == "" {
+		return true, nil
+	}
+	v := canonicalSemver(tag)
+	if !semver.IsValid(v) {
+		return false, fmt.Errorf("%q is not a valid semantic version", tag)
+	}
+
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		op, want := splitConstraintClause(clause)
+		if !semver.IsValid(want) {
+			return false, fmt.Errorf("invalid constraint clause %q", clause)
+		}
+
+		cmp := semver.Compare(v, want)
+		var ok bool
+		switch op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "==", "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		default:
+			return false, fmt.Errorf("unsupported constraint operator in %q", clause)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func splitConstraintClause(clause string) (op, version string) {
+	for _, candidate := range []string{">=", "<=", "==", "!=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(clause, candidate); ok {
+			return candidate, canonicalSemver(strings.TrimSpace(rest))
+		}
+	}
+	return "==", canonicalSemver(clause)
+}
+
+// selectRelease picks the highest semver release on channel that
+// satisfies constraint. Releases with non-semver tags are ignored so a
+// handful of malformed tags can't break selection for the rest.
+//
+// Some sources (Docker Hub tags such as "latest", SourceHut refs, or
+// repos that simply don't tag with semver) may produce no parseable
+// version at all. Rather than
+// reporting "no versions found" for every one of them — a regression
+// from the pre-semver behavior of always returning the newest entry — we
+// fall back to the channel-filtered candidate with the newest
+// PublishedAt whenever none of them parse as semver, regardless of
+// channel. That fallback only kicks in when semver parsing itself came
+// up empty, not when a constraint simply ruled everything out — a
+// constraint can't be evaluated against a non-semver tag anyway, so the
+// fallback ignores it.
+func selectRelease(releases []Release, channel, constraint string) (Release, bool) {
+	candidates := filterByChannel(releases, channel)
+
+	var matched []Release
+	var anySemver bool
+	for _, r := range candidates {
+		v := canonicalSemver(r.TagName)
+		if !semver.IsValid(v) {
+			continue
+		}
+		anySemver = true
+		ok, err := matchesConstraint(r.TagName, constraint)
+		if err != nil || !ok {
+			continue
+		}
+		matched = append(matched, r)
+	}
+
+	if len(matched) == 0 {
+		if anySemver || len(candidates) == 0 {
+			return Release{}, false
+		}
+		return newestByPublishedAt(candidates), true
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return semver.Compare(canonicalSemver(matched[i].TagName), canonicalSemver(matched[j].TagName)) > 0
+	})
+	return matched[0], true
+}
+
+// newestByPublishedAt returns the release with the latest PublishedAt,
+// used as a fallback when no release's tag parses as semver.
+func newestByPublishedAt(releases []Release) Release {
+	newest := releases[0]
+	for _, r := range releases[1:] {
+		if r.PublishedAt.After(newest.PublishedAt) {
+			newest = r
+		}
+	}
+	return newest
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanonicalSemver(t *testing.T) {
+	cases := map[string]string{
+		"1.2.3":  "v1.2.3",
+		"v1.2.3": "v1.2.3",
+		"v2":     "v2",
+	}
+	for in, want := range cases {
+		if got := canonicalSemver(in); got != want {
+			t.Errorf("canonicalSemver(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatchesConstraint(t *testing.T) {
+	cases := []struct {
+		tag        string
+		constraint string
+		want       bool
+	}{
+		{"1.5.0", "", true},
+		{"1.5.0", ">=1.2.0,<2.0.0", true},
+		{"2.0.0", ">=1.2.0,<2.0.0", false},
+		{"1.1.0", ">=1.2.0,<2.0.0", false},
+		{"v1.2.0", ">=1.2.0", true},
+		{"1.2.0", "==1.2.0", true},
+		{"1.2.0", "!=1.2.0", false},
+	}
+	for _, tc := range cases {
+		got, err := matchesConstraint(tc.tag, tc.constraint)
+		if err != nil {
+			t.Errorf("matchesConstraint(%q, %q) unexpected error: %v", tc.tag, tc.constraint, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("matchesConstraint(%q, %q) = %v, want %v", tc.tag, tc.constraint, got, tc.want)
+		}
+	}
+
+	if _, err := matchesConstraint("not-a-version", ">=1.0.0"); err == nil {
+		t.Error("matchesConstraint with a non-semver tag should error, got nil")
+	}
+}
+
+func TestSelectReleaseChannelFiltering(t *testing.T) {
+	releases := []Release{
+		{TagName: "v1.0.0-rc1", PreRelease: true},
+		{TagName: "v0.9.0"},
+		{TagName: "v2.0.0-draft", Draft: true},
+	}
+
+	// Stable drops both the prerelease and the draft, leaving v0.9.0.
+	stable, ok := selectRelease(releases, ChannelStable, "")
+	if !ok || stable.TagName != "v0.9.0" {
+		t.Fatalf("stable channel: got %+v, ok=%v, want v0.9.0", stable, ok)
+	}
+
+	// Prerelease includes stable + prerelease (but not draft); the
+	// prerelease here is semver-higher than the stable release.
+	prerelease, ok := selectRelease(releases, ChannelPrerelease, "")
+	if !ok || prerelease.TagName != "v1.0.0-rc1" {
+		t.Fatalf("prerelease channel: got %+v, ok=%v, want v1.0.0-rc1", prerelease, ok)
+	}
+
+	// Any includes everything, including the draft.
+	any, ok := selectRelease(releases, ChannelAny, "")
+	if !ok || any.TagName != "v2.0.0-draft" {
+		t.Fatalf("any channel: got %+v, ok=%v, want v2.0.0-draft", any, ok)
+	}
+}
+
+func TestSelectReleaseConstraint(t *testing.T) {
+	releases := []Release{
+		{TagName: "v1.0.0"},
+		{TagName: "v1.5.0"},
+		{TagName: "v2.0.0"},
+	}
+
+	got, ok := selectRelease(releases, ChannelStable, ">=1.0.0,<2.0.0")
+	if !ok || got.TagName != "v1.5.0" {
+		t.Fatalf("got %+v, ok=%v, want v1.5.0", got, ok)
+	}
+}
+
+func TestSelectReleaseNoMatch(t *testing.T) {
+	releases := []Release{{TagName: "v1.0.0"}}
+
+	if _, ok := selectRelease(releases, ChannelStable, ">=2.0.0"); ok {
+		t.Fatal("expected no match for an unsatisfiable constraint")
+	}
+}
+
+func TestSelectReleaseFallsBackToPublishedAtForNonSemverTags(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	releases := []Release{
+		{TagName: "release-1", PublishedAt: now.Add(-24 * time.Hour)},
+		{TagName: "release-2", PublishedAt: now},
+		{TagName: "release-0", PublishedAt: now.Add(-48 * time.Hour)},
+	}
+
+	got, ok := selectRelease(releases, ChannelAny, "")
+	if !ok || got.TagName != "release-2" {
+		t.Fatalf("got %+v, ok=%v, want release-2 (newest PublishedAt)", got, ok)
+	}
+
+	// The fallback applies on every channel, not just "any" — a new
+	// repository defaults to the stable channel, and a non-semver source
+	// shouldn't report "no versions found" just because of that default.
+	stable, ok := selectRelease(releases, ChannelStable, "")
+	if !ok || stable.TagName != "release-2" {
+		t.Fatalf("stable channel: got %+v, ok=%v, want release-2 (newest PublishedAt)", stable, ok)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestTimeout returns the REQUEST_TIMEOUT (in seconds) that each
+// handler's context should be bounded by, defaulting to 30s.
+func requestTimeout() time.Duration {
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return 30 * time.Second
+}
+
+// shutdownTimeout returns how long Shutdown waits for in-flight requests
+// to drain before giving up, defaulting to 10s.
+func shutdownTimeout() time.Duration {
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return 10 * time.Second
+}
+
+// RequestTimeoutMiddleware bounds every request's context to timeout, so
+// a slow upstream fetch or database call can't hang a handler (and, in
+// turn, a graceful shutdown) indefinitely.
+func RequestTimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}